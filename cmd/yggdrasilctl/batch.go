@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// batchRequest is one step of a -batch/-script run. Arguments are flattened
+// into the wire request alongside Request and ID (see runBatch) - the admin
+// socket has no separate parameters object. ID is optional on input; steps
+// that don't set one get an index-derived ID so the matching batchResult
+// always has something to print.
+type batchRequest struct {
+	ID        interface{}            `json:"id,omitempty" yaml:"id,omitempty"`
+	Request   string                 `json:"request" yaml:"request"`
+	Arguments map[string]interface{} `json:"arguments,omitempty" yaml:"arguments,omitempty"`
+}
+
+// batchResult is what gets printed for each batchRequest, one JSON object
+// per line, in the same order the requests were given.
+type batchResult struct {
+	ID       interface{} `json:"id,omitempty"`
+	Status   string      `json:"status"`
+	Response interface{} `json:"response,omitempty"`
+	Error    string      `json:"error,omitempty"`
+}
+
+// runBatch implements `-batch` and `-script=file.yml`: it pipelines a list
+// of requests over a single admin-socket connection and prints one result
+// per line in input order.
+//
+// The admin socket doesn't echo "id" back at the top level of a response -
+// only the whole original request object, under response["request"] (see
+// handleAll). So the id printed in each batchResult is read back out of
+// there rather than off the response itself. Matching replies to requests
+// still relies on the connection being a single ordered stream: responses
+// arrive in exactly the order their requests were written, so a FIFO queue
+// of in-flight channels (see pendingQueue) does that part of the work.
+func runBatch(cmdLineEnv *cmdLineEnv, logger *log.Logger) int {
+	requests, err := loadBatchRequests(cmdLineEnv)
+	if err != nil {
+		logger.Println("Error loading batch requests:", err)
+		return 1
+	}
+	if len(requests) == 0 {
+		logger.Println("Nothing to do: batch input was empty")
+		return 0
+	}
+
+	conn := connect(cmdLineEnv.endpoint, cmdLineEnv.transportConfig(), logger)
+	defer conn.Close()
+
+	decoder := json.NewDecoder(conn)
+	encoder := json.NewEncoder(conn)
+	var writeMu sync.Mutex
+
+	pending := newPendingQueue()
+
+	go func() {
+		for {
+			var raw map[string]interface{}
+			if err := decoder.Decode(&raw); err != nil {
+				pending.closeAll()
+				return
+			}
+			ch, ok := pending.pop()
+			if !ok {
+				continue
+			}
+			ch <- batchResult{
+				ID:       echoedRequestID(raw),
+				Status:   fmt.Sprint(raw["status"]),
+				Response: raw["response"],
+				Error:    fmt.Sprint(raw["error"]),
+			}
+		}
+	}()
+
+	parallel := cmdLineEnv.batchParallel
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	results := make([]batchResult, len(requests))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	var halted bool
+	var haltedMu sync.Mutex
+
+	for i, req := range requests {
+		id := fmt.Sprint(req.ID)
+		if id == "" || id == "<nil>" {
+			id = fmt.Sprintf("%d", i)
+		}
+
+		// Take a slot before checking halted, so that with -parallel=1 the
+		// previous request has finished (and set halted) by the time this
+		// one decides whether to go out.
+		sem <- struct{}{}
+		haltedMu.Lock()
+		stop := halted
+		haltedMu.Unlock()
+		if stop && cmdLineEnv.haltOnError {
+			<-sem
+			results[i] = batchResult{ID: id, Status: "skipped"}
+			continue
+		}
+
+		// Parameters go in as flat top-level keys, same as buildRequest -
+		// the admin socket has no notion of a nested "arguments" object.
+		send := make(admin_info, len(req.Arguments)+2)
+		for k, v := range req.Arguments {
+			send[k] = v
+		}
+		send["id"] = id
+		send["request"] = req.Request
+
+		wg.Add(1)
+		go func(i int, id string, send admin_info) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			ch := make(chan batchResult, 1)
+
+			writeMu.Lock()
+			pending.push(ch)
+			err := encoder.Encode(&send)
+			if err != nil {
+				pending.cancelLast(ch)
+			}
+			writeMu.Unlock()
+			if err != nil {
+				results[i] = batchResult{ID: id, Status: "error", Error: err.Error()}
+				return
+			}
+
+			select {
+			case res, ok := <-ch:
+				if !ok {
+					results[i] = batchResult{ID: id, Status: "error", Error: "connection closed before a response arrived"}
+					return
+				}
+				results[i] = res
+			case <-time.After(30 * time.Second):
+				results[i] = batchResult{ID: id, Status: "error", Error: "timed out waiting for a response"}
+			}
+
+			if results[i].Status == "error" && cmdLineEnv.haltOnError {
+				haltedMu.Lock()
+				halted = true
+				haltedMu.Unlock()
+			}
+		}(i, id, send)
+	}
+
+	wg.Wait()
+
+	exitCode := 0
+	for _, res := range results {
+		if out, err := json.Marshal(res); err == nil {
+			fmt.Println(string(out))
+		}
+		if res.Status == "error" {
+			exitCode = 1
+		}
+	}
+
+	return exitCode
+}
+
+// echoedRequestID pulls the "id" back out of a decoded response's echoed
+// copy of the original request (response["request"]["id"]), falling back
+// to nil if the response is malformed or carries no id.
+func echoedRequestID(raw map[string]interface{}) interface{} {
+	req, ok := raw["request"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	return req["id"]
+}
+
+// loadBatchRequests reads the list of steps either from a YAML playbook
+// (-script=file.yml) or, by default, from newline-delimited JSON on
+// stdin - one {"request":..,"arguments":{...}} object per line.
+func loadBatchRequests(cmdLineEnv *cmdLineEnv) ([]batchRequest, error) {
+	if cmdLineEnv.script != "" {
+		data, err := os.ReadFile(cmdLineEnv.script)
+		if err != nil {
+			return nil, fmt.Errorf("reading script file: %w", err)
+		}
+		var requests []batchRequest
+		if err := yaml.Unmarshal(data, &requests); err != nil {
+			return nil, fmt.Errorf("parsing script file: %w", err)
+		}
+		return requests, nil
+	}
+
+	var requests []batchRequest
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var req batchRequest
+		if err := json.Unmarshal(line, &req); err != nil {
+			return nil, fmt.Errorf("parsing batch line %q: %w", line, err)
+		}
+		requests = append(requests, req)
+	}
+	return requests, scanner.Err()
+}
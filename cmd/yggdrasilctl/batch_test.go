@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestEchoedRequestID(t *testing.T) {
+	cases := []struct {
+		name string
+		raw  map[string]interface{}
+		want interface{}
+	}{
+		{
+			"id present in echoed request",
+			map[string]interface{}{"request": map[string]interface{}{"id": "3", "request": "getSelf"}},
+			"3",
+		},
+		{
+			"request not echoed back",
+			map[string]interface{}{"status": "success"},
+			nil,
+		},
+		{
+			"request echoed but carries no id",
+			map[string]interface{}{"request": map[string]interface{}{"request": "getSelf"}},
+			nil,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := echoedRequestID(c.raw); got != c.want {
+				t.Errorf("echoedRequestID(%v) = %v, want %v", c.raw, got, c.want)
+			}
+		})
+	}
+}
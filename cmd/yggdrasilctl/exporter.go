@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yggdrasil-network/yggdrasil-go/cmd/yggdrasilctl/metrics"
+)
+
+// exporterRequests are the admin requests polled on every scrape interval.
+// They cover everything the yggdrasil_* series in metrics/openmetrics.go
+// need.
+var exporterRequests = []string{"getPeers", "getSwitchQueues", "getSessions", "getDHT", "getSelf"}
+
+// runExporter runs `yggdrasilctl exporter -listen=:9101`: an HTTP server
+// exposing /metrics in Prometheus text format, backed by a background poll
+// of the admin socket on cmdLineEnv.exporterInterval.
+func runExporter(cmdLineEnv *cmdLineEnv, logger *log.Logger) int {
+	interval := cmdLineEnv.exporterInterval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	var mu sync.Mutex
+	var latest []byte
+
+	poll := func() {
+		conn := connect(cmdLineEnv.endpoint, cmdLineEnv.transportConfig(), logger)
+		defer conn.Close()
+
+		encoder := json.NewEncoder(conn)
+		decoder := json.NewDecoder(conn)
+
+		var buf bytes.Buffer
+		now := time.Now()
+
+		for _, request := range exporterRequests {
+			send := buildRequest([]string{request}, logger)
+			recv, err := roundTrip(encoder, decoder, send)
+			if err != nil {
+				logger.Println("Exporter: error polling", request, err)
+				continue
+			}
+			res, ok := recv["response"].(map[string]interface{})
+			if !ok {
+				logger.Println("Exporter: malformed response to", request)
+				continue
+			}
+			switch strings.ToLower(request) {
+			case "getpeers":
+				metrics.WritePeerMetrics(&buf, res)
+			case "getswitchqueues":
+				metrics.WriteSwitchQueueMetrics(&buf, res)
+			case "getsessions":
+				metrics.WriteSessionMetrics(&buf, res)
+			case "getdht":
+				metrics.WriteDHTMetrics(&buf, res, now)
+			case "getself":
+				metrics.WriteSelfMetric(&buf, res)
+			}
+		}
+
+		mu.Lock()
+		latest = append(latest[:0], buf.Bytes()...)
+		mu.Unlock()
+	}
+
+	poll()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			poll()
+		}
+	}()
+
+	http.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write(latest)
+	})
+
+	logger.Println("Exporter listening on", cmdLineEnv.exporterListen)
+	if err := http.ListenAndServe(cmdLineEnv.exporterListen, nil); err != nil {
+		logger.Println("Exporter failed:", err)
+		return 1
+	}
+
+	return 0
+}
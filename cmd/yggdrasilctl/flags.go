@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"time"
+
+	"github.com/yggdrasil-network/yggdrasil-go/cmd/yggdrasilctl/transport"
+)
+
+// cmdLineEnv holds the parsed flags and positional arguments for one
+// yggdrasilctl invocation.
+type cmdLineEnv struct {
+	endpoint string
+	ver      bool
+	injson   bool
+	verbose  bool
+	args     []string
+
+	psk          string
+	clientCert   string
+	clientKey    string
+	rootCA       string
+	identityFile string
+	hostKey      string
+
+	watch  time.Duration
+	follow bool
+
+	exporterListen   string
+	exporterInterval time.Duration
+
+	batch         bool
+	script        string
+	batchParallel int
+	haltOnError   bool
+}
+
+func newCmdLineEnv() *cmdLineEnv {
+	return &cmdLineEnv{}
+}
+
+func (c *cmdLineEnv) parseFlagsAndArgs() {
+	endpoint := flag.String("endpoint", "unix:///var/run/yggdrasil.sock", "Admin socket endpoint")
+	ver := flag.Bool("version", false, "Print version information and exit")
+	injson := flag.Bool("json", false, "Print the response as indented JSON")
+	verbose := flag.Bool("verbose", false, "Print extra fields that are hidden by default")
+
+	psk := flag.String("psk", "", "Pin the tls:// server certificate's SHA-256 fingerprint")
+	clientCert := flag.String("cert", "", "Client certificate presented for tls:// auth")
+	clientKey := flag.String("key", "", "Private key matching -cert")
+	rootCA := flag.String("cacert", "", "CA certificate to verify the tls:// server against")
+	identityFile := flag.String("identity", "", "SSH private key for ssh:// auth when no agent is running")
+	hostKey := flag.String("hostkey", "", "Pin the ssh:// server host key's SHA-256 fingerprint")
+
+	watch := flag.Duration("watch", 0, "Re-issue the request every DURATION and redraw the table in place")
+	follow := flag.Bool("follow", false, "Keep polling with -watch until interrupted, instead of stopping after one refresh")
+
+	exporterListen := flag.String("listen", ":9101", "Address for the exporter subcommand's HTTP server to listen on")
+	exporterInterval := flag.Duration("interval", 10*time.Second, "Polling interval for the exporter subcommand")
+
+	batch := flag.Bool("batch", false, "Read newline-delimited JSON requests from stdin and pipeline them over one connection")
+	script := flag.String("script", "", "Read a YAML playbook of requests from file instead of stdin")
+	batchParallel := flag.Int("parallel", 1, "Number of batch requests to have in flight at once")
+	haltOnError := flag.Bool("halt-on-error", false, "Stop submitting further batch requests after the first error")
+
+	flag.Parse()
+
+	c.endpoint = *endpoint
+	c.ver = *ver
+	c.injson = *injson
+	c.verbose = *verbose
+	c.psk = *psk
+	c.clientCert = *clientCert
+	c.clientKey = *clientKey
+	c.rootCA = *rootCA
+	c.identityFile = *identityFile
+	c.hostKey = *hostKey
+	c.watch = *watch
+	c.follow = *follow
+	c.exporterListen = *exporterListen
+	c.exporterInterval = *exporterInterval
+	c.batch = *batch
+	c.script = *script
+	c.batchParallel = *batchParallel
+	c.haltOnError = *haltOnError
+
+	c.args = flag.Args()
+}
+
+func (c *cmdLineEnv) setEndpoint(logger *log.Logger) {
+	logger.Println("Using endpoint:", c.endpoint)
+}
+
+// transportConfig builds the transport.Config carrying whatever
+// authentication material was passed on the command line, for the tls://,
+// wss:// and ssh:// dialers to fall back on when the endpoint's own query
+// string doesn't already specify it.
+func (c *cmdLineEnv) transportConfig() *transport.Config {
+	return &transport.Config{
+		PSK:                c.psk,
+		ClientCert:         c.clientCert,
+		ClientKey:          c.clientKey,
+		RootCA:             c.rootCA,
+		IdentityFile:       c.identityFile,
+		HostKeyFingerprint: c.hostKey,
+	}
+}
@@ -3,17 +3,18 @@ package main
 import (
 	"bytes"
 	"encoding/json"
-	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"net"
-	"net/url"
 	"os"
-	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/yggdrasil-network/yggdrasil-go/cmd/yggdrasilctl/metrics"
+	"github.com/yggdrasil-network/yggdrasil-go/cmd/yggdrasilctl/renderer"
+	"github.com/yggdrasil-network/yggdrasil-go/cmd/yggdrasilctl/transport"
 	"github.com/yggdrasil-network/yggdrasil-go/src/version"
 )
 
@@ -37,6 +38,8 @@ func run() int {
 		return 0
 	}()
 
+	// cmdLineEnv now also parses -watch=DURATION and -follow; see
+	// runWatch below for how they drive the polling loop.
 	cmdLineEnv := newCmdLineEnv()
 	cmdLineEnv.parseFlagsAndArgs()
 
@@ -52,18 +55,87 @@ func run() int {
 		return 0
 	}
 
+	if cmdLineEnv.args[0] == "shell" {
+		cmdLineEnv.setEndpoint(logger)
+		return runShell(cmdLineEnv, logger)
+	}
+
+	if cmdLineEnv.args[0] == "exporter" {
+		// exporter reuses -listen=:9101 and -interval=DURATION parsed by
+		// cmdLineEnv into exporterListen/exporterInterval.
+		cmdLineEnv.setEndpoint(logger)
+		return runExporter(cmdLineEnv, logger)
+	}
+
+	if cmdLineEnv.batch || cmdLineEnv.script != "" {
+		// -batch/-script/-halt-on-error/-parallel are parsed into the
+		// matching cmdLineEnv fields consumed by runBatch.
+		cmdLineEnv.setEndpoint(logger)
+		return runBatch(cmdLineEnv, logger)
+	}
+
 	cmdLineEnv.setEndpoint(logger)
 
-	conn := connect(cmdLineEnv.endpoint, logger)
+	conn := connect(cmdLineEnv.endpoint, cmdLineEnv.transportConfig(), logger)
 	logger.Println("Connected")
 	defer conn.Close()
 
 	decoder := json.NewDecoder(conn)
 	encoder := json.NewEncoder(conn)
+	send := buildRequest(cmdLineEnv.args, logger)
+
+	if cmdLineEnv.watch > 0 {
+		return runWatch(encoder, decoder, send, cmdLineEnv, logger)
+	}
+
+	recv, err := roundTrip(encoder, decoder, send)
+	if err != nil {
+		logger.Println("Error receiving response:", err)
+		return 1
+	}
+
+	logger.Printf("Response received")
+	if recv["status"] == "error" {
+		if err, ok := recv["error"]; ok {
+			fmt.Println("Admin socket returned an error:", err)
+		} else {
+			fmt.Println("Admin socket returned an error but didn't specify any error text")
+		}
+		return 1
+	}
+	if _, ok := recv["request"]; !ok {
+		fmt.Println("Missing request in response (malformed response?)")
+		return 1
+	}
+	if _, ok := recv["response"]; !ok {
+		fmt.Println("Missing response body (malformed response?)")
+		return 1
+	}
+	res := recv["response"].(map[string]interface{})
+
+	if cmdLineEnv.injson {
+		if json, err := json.MarshalIndent(res, "", "  "); err == nil {
+			fmt.Println(string(json))
+		}
+		return 0
+	}
+
+	handleAll(recv, cmdLineEnv.verbose)
+
+	if v, ok := recv["status"]; ok && v != "success" {
+		return 1
+	}
+
+	return 0
+}
+
+// buildRequest turns the positional command-line arguments (request verb
+// followed by key=value parameters) into the admin_info object that gets
+// JSON-encoded onto the wire.
+func buildRequest(args []string, logger *log.Logger) admin_info {
 	send := make(admin_info)
-	recv := make(admin_info)
 
-	for c, a := range cmdLineEnv.args {
+	for c, a := range args {
 		if c == 0 {
 			if strings.HasPrefix(a, "-") {
 				logger.Printf("Ignoring flag %s as it should be specified before other parameters\n", a)
@@ -96,73 +168,86 @@ func run() int {
 		}
 	}
 
+	return send
+}
+
+// roundTrip sends send and reads back exactly one response. The admin
+// socket answers requests in order on the same connection, which is what
+// lets runWatch reuse one roundTrip per tick instead of reconnecting.
+func roundTrip(encoder *json.Encoder, decoder *json.Decoder, send admin_info) (admin_info, error) {
 	if err := encoder.Encode(&send); err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	logger.Printf("Request sent")
+	recv := make(admin_info)
+	if err := decoder.Decode(&recv); err != nil {
+		return nil, err
+	}
+
+	return recv, nil
+}
+
+// runWatch re-issues send on an interval and re-renders the response in
+// place, so commands like getPeers/getDHT/getSessions can be left running
+// instead of being piped through watch(1). The underlying table keeps a
+// snapshot between ticks so it can show rates instead of raw counters.
+func runWatch(encoder *json.Encoder, decoder *json.Decoder, send admin_info, cmdLineEnv *cmdLineEnv, logger *log.Logger) int {
+	isTTY := isTerminal(os.Stdout)
+	table := renderer.NewTable(cmdLineEnv.verbose)
+
+	ticker := time.NewTicker(cmdLineEnv.watch)
+	defer ticker.Stop()
+
+	for {
+		recv, err := roundTrip(encoder, decoder, send)
+		if err != nil {
+			logger.Println("Error receiving response:", err)
+			return 1
+		}
 
-	if err := decoder.Decode(&recv); err == nil {
-		logger.Printf("Response received")
 		if recv["status"] == "error" {
 			if err, ok := recv["error"]; ok {
 				fmt.Println("Admin socket returned an error:", err)
-			} else {
-				fmt.Println("Admin socket returned an error but didn't specify any error text")
 			}
 			return 1
 		}
-		if _, ok := recv["request"]; !ok {
-			fmt.Println("Missing request in response (malformed response?)")
-			return 1
-		}
-		if _, ok := recv["response"]; !ok {
+
+		res, ok := recv["response"].(map[string]interface{})
+		if !ok {
 			fmt.Println("Missing response body (malformed response?)")
 			return 1
 		}
-		res := recv["response"].(map[string]interface{})
 
-		if cmdLineEnv.injson {
-			if json, err := json.MarshalIndent(res, "", "  "); err == nil {
-				fmt.Println(string(json))
-			}
+		if isTTY {
+			// move the cursor to the top-left and clear to the end of
+			// the screen before redrawing, rather than scrolling.
+			fmt.Print("\033[H\033[2J")
+		}
+		fmt.Println(time.Now().Format("15:04:05"), send["request"])
+		table.Render(res)
+
+		if !cmdLineEnv.follow {
 			return 0
 		}
 
-		handleAll(recv, cmdLineEnv.verbose)
-	} else {
-		logger.Println("Error receiving response:", err)
+		<-ticker.C
 	}
+}
 
-	if v, ok := recv["status"]; ok && v != "success" {
-		return 1
+// isTerminal reports whether f looks like a TTY, so runWatch knows whether
+// it's safe to reset the cursor or whether it should just reprint plainly.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
 	}
-
-	return 0
+	return info.Mode()&os.ModeCharDevice != 0
 }
 
-func connect(endpoint string, logger *log.Logger) net.Conn {
-	var conn net.Conn
-
-	u, err := url.Parse(endpoint)
-
-	if err == nil {
-		switch strings.ToLower(u.Scheme) {
-		case "unix":
-			logger.Println("Connecting to UNIX socket", endpoint[7:])
-			conn, err = net.Dial("unix", endpoint[7:])
-		case "tcp":
-			logger.Println("Connecting to TCP socket", u.Host)
-			conn, err = net.Dial("tcp", u.Host)
-		default:
-			logger.Println("Unknown protocol or malformed address - check your endpoint")
-			err = errors.New("protocol not supported")
-		}
-	} else {
-		logger.Println("Connecting to TCP socket", u.Host)
-		conn, err = net.Dial("tcp", endpoint)
-	}
+func connect(endpoint string, cfg *transport.Config, logger *log.Logger) net.Conn {
+	logger.Println("Connecting to", endpoint)
 
+	conn, err := transport.Dial(endpoint, cfg)
 	if err != nil {
 		panic(err)
 	}
@@ -211,66 +296,7 @@ func handleDot(res map[string]interface{}) {
 }
 
 func handleVariousInfo(res map[string]interface{}, verbose bool) {
-	maxWidths := make(map[string]int)
-	var keyOrder []string
-	keysOrdered := false
-
-	for _, tlv := range res {
-		for slk, slv := range tlv.(map[string]interface{}) {
-			if !keysOrdered {
-				for k := range slv.(map[string]interface{}) {
-					if !verbose {
-						if k == "box_pub_key" || k == "box_sig_key" || k == "nodeinfo" || k == "was_mtu_fixed" {
-							continue
-						}
-					}
-					keyOrder = append(keyOrder, fmt.Sprint(k))
-				}
-				sort.Strings(keyOrder)
-				keysOrdered = true
-			}
-			for k, v := range slv.(map[string]interface{}) {
-				if len(fmt.Sprint(slk)) > maxWidths["key"] {
-					maxWidths["key"] = len(fmt.Sprint(slk))
-				}
-				if len(fmt.Sprint(v)) > maxWidths[k] {
-					maxWidths[k] = len(fmt.Sprint(v))
-					if maxWidths[k] < len(k) {
-						maxWidths[k] = len(k)
-					}
-				}
-			}
-		}
-
-		if len(keyOrder) > 0 {
-			fmt.Printf("%-"+fmt.Sprint(maxWidths["key"])+"s  ", "")
-			for _, v := range keyOrder {
-				fmt.Printf("%-"+fmt.Sprint(maxWidths[v])+"s  ", v)
-			}
-			fmt.Println()
-		}
-
-		for slk, slv := range tlv.(map[string]interface{}) {
-			fmt.Printf("%-"+fmt.Sprint(maxWidths["key"])+"s  ", slk)
-			for _, k := range keyOrder {
-				preformatted := slv.(map[string]interface{})[k]
-				var formatted string
-				switch k {
-				case "bytes_sent", "bytes_recvd":
-					formatted = fmt.Sprintf("%d", uint(preformatted.(float64)))
-				case "uptime", "last_seen":
-					seconds := uint(preformatted.(float64)) % 60
-					minutes := uint(preformatted.(float64)/60) % 60
-					hours := uint(preformatted.(float64) / 60 / 60)
-					formatted = fmt.Sprintf("%02d:%02d:%02d", hours, minutes, seconds)
-				default:
-					formatted = fmt.Sprint(preformatted)
-				}
-				fmt.Printf("%-"+fmt.Sprint(maxWidths[k])+"s  ", formatted)
-			}
-			fmt.Println()
-		}
-	}
+	renderer.NewTable(verbose).Render(res)
 }
 
 func handleGetAndSetTunTap(res map[string]interface{}) {
@@ -318,55 +344,49 @@ func handleGetSelf(res map[string]interface{}, verbose bool) {
 }
 
 func handleGetSwitchQueues(res map[string]interface{}) {
-	maximumqueuesize := float64(4194304)
-	portqueues := make(map[float64]float64)
-	portqueuesize := make(map[float64]float64)
-	portqueuepackets := make(map[float64]float64)
-	v := res["switchqueues"].(map[string]interface{})
-	if queuecount, ok := v["queues_count"].(float64); ok {
-		fmt.Printf("Active queue count: %d queues\n", uint(queuecount))
-	}
-	if queuesize, ok := v["queues_size"].(float64); ok {
-		fmt.Printf("Active queue size: %d bytes\n", uint(queuesize))
-	}
-	if highestqueuecount, ok := v["highest_queues_count"].(float64); ok {
-		fmt.Printf("Highest queue count: %d queues\n", uint(highestqueuecount))
-	}
-	if highestqueuesize, ok := v["highest_queues_size"].(float64); ok {
-		fmt.Printf("Highest queue size: %d bytes\n", uint(highestqueuesize))
-	}
-	if m, ok := v["maximum_queues_size"].(float64); ok {
-		maximumqueuesize = m
-		fmt.Printf("Maximum queue size: %d bytes\n", uint(maximumqueuesize))
-	}
-	if queues, ok := v["queues"].([]interface{}); ok {
-		if len(queues) != 0 {
-			fmt.Println("Active queues:")
-			for _, v := range queues {
-				queueport := v.(map[string]interface{})["queue_port"].(float64)
-				queuesize := v.(map[string]interface{})["queue_size"].(float64)
-				queuepackets := v.(map[string]interface{})["queue_packets"].(float64)
-				queueid := v.(map[string]interface{})["queue_id"].(string)
-				portqueues[queueport]++
-				portqueuesize[queueport] += queuesize
-				portqueuepackets[queueport] += queuepackets
-				queuesizepercent := (100 / maximumqueuesize) * queuesize
-				fmt.Printf("- Switch port %d, Stream ID: %v, size: %d bytes (%d%% full), %d packets\n",
-					uint(queueport), []byte(queueid), uint(queuesize),
-					uint(queuesizepercent), uint(queuepackets))
-			}
+	sq := metrics.ParseSwitchQueues(res)
+
+	fmt.Printf("Active queue count: %d queues\n", sq.ActiveCount)
+	fmt.Printf("Active queue size: %d bytes\n", sq.ActiveSize)
+	fmt.Printf("Highest queue count: %d queues\n", sq.HighestCount)
+	fmt.Printf("Highest queue size: %d bytes\n", sq.HighestSize)
+	fmt.Printf("Maximum queue size: %d bytes\n", sq.MaximumSize)
+
+	portQueues := make(map[uint64]uint64)
+	portQueueSize := make(map[uint64]uint64)
+	portQueuePackets := make(map[uint64]uint64)
+
+	if len(sq.Queues) != 0 {
+		fmt.Println("Active queues:")
+		for _, q := range sq.Queues {
+			portQueues[q.Port]++
+			portQueueSize[q.Port] += q.Size
+			portQueuePackets[q.Port] += q.Packets
+			fmt.Printf("- Switch port %d, Stream ID: %v, size: %d bytes (%d%% full), %d packets\n",
+				q.Port, []byte(q.ID), q.Size, queueSizePercent(q.Size, sq.MaximumSize), q.Packets)
 		}
 	}
-	if len(portqueuesize) > 0 && len(portqueuepackets) > 0 {
+
+	if len(portQueueSize) > 0 && len(portQueuePackets) > 0 {
 		fmt.Println("Aggregated statistics by switchport:")
-		for k, v := range portqueuesize {
-			queuesizepercent := (100 / (portqueues[k] * maximumqueuesize)) * v
+		for port, size := range portQueueSize {
 			fmt.Printf("- Switch port %d, size: %d bytes (%d%% full), %d packets\n",
-				uint(k), uint(v), uint(queuesizepercent), uint(portqueuepackets[k]))
+				port, size, queueSizePercent(size, portQueues[port]*sq.MaximumSize), portQueuePackets[port])
 		}
 	}
 }
 
+// queueSizePercent returns how full a queue is, as a percentage of max.
+// The node can report a maximum_queues_size of 0 (e.g. before it's
+// finished starting up), so this guards the division rather than letting
+// it panic.
+func queueSizePercent(size, max uint64) uint64 {
+	if max == 0 {
+		return 0
+	}
+	return (100 * size) / max
+}
+
 func handleAddsAndRemoves(res map[string]interface{}) {
 	if _, ok := res["added"]; ok {
 		for _, v := range res["added"].([]interface{}) {
@@ -0,0 +1,143 @@
+// Package metrics parses admin socket responses into a shape shared by
+// the CLI's tables and the exporter subcommand's Prometheus gauges.
+package metrics
+
+import "fmt"
+
+// Entry is one row of a getPeers/getSessions/getDHT/getSwitchPeers
+// response: a key (an IP, a box_pub_key, coords - whatever the request
+// keys its rows by) plus its fields, still as the raw JSON types.
+type Entry struct {
+	Key    string
+	Fields map[string]interface{}
+}
+
+// Entries walks the {category: {key: {field: value}}} shape that
+// getPeers/getSessions/getDHT/getSwitchPeers all return and flattens it
+// into a slice of Entry.
+func Entries(res map[string]interface{}) []Entry {
+	var entries []Entry
+	for _, tlv := range res {
+		category, ok := tlv.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for key, fields := range category {
+			f, ok := fields.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			entries = append(entries, Entry{Key: fmt.Sprint(key), Fields: f})
+		}
+	}
+	return entries
+}
+
+// Float reads a field as a float64, defaulting to 0 when it's absent or
+// of the wrong type - admin responses unmarshal numbers as float64, so
+// this is the one conversion every caller needs.
+func (e Entry) Float(field string) float64 {
+	f, _ := e.Fields[field].(float64)
+	return f
+}
+
+// String reads a field as a string, defaulting to "".
+func (e Entry) String(field string) string {
+	s, _ := e.Fields[field].(string)
+	return s
+}
+
+// SwitchQueues is the parsed form of a getSwitchQueues response.
+type SwitchQueues struct {
+	ActiveCount  uint64
+	ActiveSize   uint64
+	HighestCount uint64
+	HighestSize  uint64
+	MaximumSize  uint64
+	Queues       []QueueEntry
+}
+
+// QueueEntry is one entry in SwitchQueues.Queues.
+type QueueEntry struct {
+	Port    uint64
+	Size    uint64
+	Packets uint64
+	ID      string
+}
+
+// ParseSwitchQueues extracts the counters and per-queue entries out of a
+// getSwitchQueues response. handleGetSwitchQueues and the exporter both
+// call this instead of re-walking res["switchqueues"] themselves.
+func ParseSwitchQueues(res map[string]interface{}) SwitchQueues {
+	sq := SwitchQueues{MaximumSize: 4194304}
+
+	v, ok := res["switchqueues"].(map[string]interface{})
+	if !ok {
+		return sq
+	}
+
+	if c, ok := v["queues_count"].(float64); ok {
+		sq.ActiveCount = uint64(c)
+	}
+	if s, ok := v["queues_size"].(float64); ok {
+		sq.ActiveSize = uint64(s)
+	}
+	if c, ok := v["highest_queues_count"].(float64); ok {
+		sq.HighestCount = uint64(c)
+	}
+	if s, ok := v["highest_queues_size"].(float64); ok {
+		sq.HighestSize = uint64(s)
+	}
+	if m, ok := v["maximum_queues_size"].(float64); ok {
+		sq.MaximumSize = uint64(m)
+	}
+
+	if queues, ok := v["queues"].([]interface{}); ok {
+		for _, q := range queues {
+			qm, ok := q.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			var entry QueueEntry
+			if port, ok := qm["queue_port"].(float64); ok {
+				entry.Port = uint64(port)
+			}
+			if size, ok := qm["queue_size"].(float64); ok {
+				entry.Size = uint64(size)
+			}
+			if packets, ok := qm["queue_packets"].(float64); ok {
+				entry.Packets = uint64(packets)
+			}
+			entry.ID, _ = qm["queue_id"].(string)
+			sq.Queues = append(sq.Queues, entry)
+		}
+	}
+
+	return sq
+}
+
+// Self is the parsed form of a getSelf response.
+type Self struct {
+	BuildVersion string
+	Coords       string
+}
+
+// ParseSelf extracts the fields of a getSelf response that are useful as
+// exporter labels.
+func ParseSelf(res map[string]interface{}) Self {
+	var self Self
+	selfMap, ok := res["self"].(map[string]interface{})
+	if !ok {
+		return self
+	}
+	for _, v := range selfMap {
+		fields, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		self.BuildVersion, _ = fields["build_version"].(string)
+		self.Coords, _ = fields["coords"].(string)
+		break
+	}
+	return self
+}
@@ -0,0 +1,105 @@
+package metrics
+
+import "testing"
+
+func TestParseSwitchQueues(t *testing.T) {
+	res := map[string]interface{}{
+		"switchqueues": map[string]interface{}{
+			"queues_count":         float64(2),
+			"queues_size":          float64(4096),
+			"highest_queues_count": float64(3),
+			"highest_queues_size":  float64(8192),
+			"maximum_queues_size":  float64(4194304),
+			"queues": []interface{}{
+				map[string]interface{}{
+					"queue_port":    float64(1),
+					"queue_size":    float64(2048),
+					"queue_packets": float64(4),
+					"queue_id":      "abcd",
+				},
+				map[string]interface{}{
+					"queue_port":    float64(1),
+					"queue_size":    float64(2048),
+					"queue_packets": float64(6),
+					"queue_id":      "efgh",
+				},
+			},
+		},
+	}
+
+	sq := ParseSwitchQueues(res)
+
+	if sq.ActiveCount != 2 || sq.ActiveSize != 4096 {
+		t.Errorf("unexpected active counters: %+v", sq)
+	}
+	if sq.HighestCount != 3 || sq.HighestSize != 8192 {
+		t.Errorf("unexpected highest counters: %+v", sq)
+	}
+	if sq.MaximumSize != 4194304 {
+		t.Errorf("unexpected maximum size: %d", sq.MaximumSize)
+	}
+	if len(sq.Queues) != 2 {
+		t.Fatalf("expected 2 queue entries, got %d", len(sq.Queues))
+	}
+	if sq.Queues[0].Port != 1 || sq.Queues[0].ID != "abcd" {
+		t.Errorf("unexpected first queue entry: %+v", sq.Queues[0])
+	}
+}
+
+func TestParseSwitchQueuesMissingSection(t *testing.T) {
+	sq := ParseSwitchQueues(map[string]interface{}{})
+
+	if sq.MaximumSize != 4194304 {
+		t.Errorf("expected default maximum size when switchqueues is absent, got %d", sq.MaximumSize)
+	}
+	if len(sq.Queues) != 0 {
+		t.Errorf("expected no queues when switchqueues is absent, got %d", len(sq.Queues))
+	}
+}
+
+func TestParseSwitchQueuesMalformedEntry(t *testing.T) {
+	res := map[string]interface{}{
+		"switchqueues": map[string]interface{}{
+			"queues": []interface{}{
+				map[string]interface{}{
+					"queue_port": float64(1),
+					// queue_size, queue_packets and queue_id all missing.
+				},
+				"not even a map",
+			},
+		},
+	}
+
+	sq := ParseSwitchQueues(res)
+
+	if len(sq.Queues) != 1 {
+		t.Fatalf("expected the malformed-but-map entry to survive and the non-map one to be skipped, got %d entries", len(sq.Queues))
+	}
+	if sq.Queues[0].Port != 1 || sq.Queues[0].Size != 0 || sq.Queues[0].Packets != 0 || sq.Queues[0].ID != "" {
+		t.Errorf("unexpected entry for partially-populated queue: %+v", sq.Queues[0])
+	}
+}
+
+func TestEntries(t *testing.T) {
+	res := map[string]interface{}{
+		"peers": map[string]interface{}{
+			"10.0.0.1": map[string]interface{}{
+				"bytes_sent": float64(10),
+			},
+		},
+	}
+
+	entries := Entries(res)
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	if entries[0].Key != "10.0.0.1" {
+		t.Errorf("unexpected entry key: %q", entries[0].Key)
+	}
+	if got := entries[0].Float("bytes_sent"); got != 10 {
+		t.Errorf("Float(bytes_sent) = %v, want 10", got)
+	}
+	if got := entries[0].Float("missing"); got != 0 {
+		t.Errorf("Float(missing) = %v, want 0", got)
+	}
+}
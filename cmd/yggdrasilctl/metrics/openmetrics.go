@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// WritePeerMetrics renders a getPeers response as yggdrasil_peer_* series.
+// bytes_sent/bytes_recvd come off the node as monotonic counters, so they
+// are emitted with the _total suffix Prometheus expects for counters.
+func WritePeerMetrics(w io.Writer, res map[string]interface{}) {
+	for _, e := range Entries(res) {
+		labels := fmt.Sprintf(`remote=%q,box_pub_key=%q,port="%d"`,
+			e.Key, e.String("box_pub_key"), uint64(e.Float("port")))
+		fmt.Fprintf(w, "yggdrasil_peer_bytes_sent_total{%s} %d\n", labels, uint64(e.Float("bytes_sent")))
+		fmt.Fprintf(w, "yggdrasil_peer_bytes_recvd_total{%s} %d\n", labels, uint64(e.Float("bytes_recvd")))
+		fmt.Fprintf(w, "yggdrasil_peer_uptime_seconds{%s} %d\n", labels, uint64(e.Float("uptime")))
+	}
+}
+
+// WriteSwitchQueueMetrics renders a getSwitchQueues response as
+// yggdrasil_switch_queue_* gauges, one series per active queue port.
+func WriteSwitchQueueMetrics(w io.Writer, res map[string]interface{}) {
+	sq := ParseSwitchQueues(res)
+	bySizePort := make(map[uint64]uint64)
+	byPacketsPort := make(map[uint64]uint64)
+	for _, q := range sq.Queues {
+		bySizePort[q.Port] += q.Size
+		byPacketsPort[q.Port] += q.Packets
+	}
+	for port, size := range bySizePort {
+		fmt.Fprintf(w, "yggdrasil_switch_queue_size_bytes{port=\"%d\"} %d\n", port, size)
+		fmt.Fprintf(w, "yggdrasil_switch_queue_packets{port=\"%d\"} %d\n", port, byPacketsPort[port])
+	}
+}
+
+// WriteSessionMetrics renders a getSessions response. uptime is converted
+// to a gauge in seconds, matching the CLI table's own hh:mm:ss rendering.
+func WriteSessionMetrics(w io.Writer, res map[string]interface{}) {
+	for _, e := range Entries(res) {
+		fmt.Fprintf(w, "yggdrasil_session_uptime_seconds{remote=%q} %d\n", e.Key, uint64(e.Float("uptime")))
+	}
+}
+
+// WriteDHTMetrics renders a getDHT response, converting the node's
+// "seconds since last seen" into a Unix-epoch gauge - the form Prometheus
+// recording rules expect for staleness checks.
+func WriteDHTMetrics(w io.Writer, res map[string]interface{}, now time.Time) {
+	for _, e := range Entries(res) {
+		lastSeen := now.Add(-time.Duration(e.Float("last_seen")) * time.Second)
+		fmt.Fprintf(w, "yggdrasil_dht_last_seen_seconds{key=%q} %d\n", e.Key, lastSeen.Unix())
+	}
+}
+
+// WriteSelfMetric renders a getSelf response as a single info-style gauge,
+// following the usual `_info{labels} 1` convention for exposing build/
+// version labels that have no natural numeric value.
+func WriteSelfMetric(w io.Writer, res map[string]interface{}) {
+	self := ParseSelf(res)
+	fmt.Fprintf(w, "yggdrasil_self_info{build_version=%q,coords=%q} 1\n", self.BuildVersion, self.Coords)
+}
@@ -0,0 +1,65 @@
+package main
+
+import "sync"
+
+// pendingQueue correlates batch responses with the requests that caused
+// them. The echoed "id" in response["request"]["id"] identifies which
+// request a response belongs to, but it can't be looked up by value - the
+// response that carries it has to be matched to an in-flight request
+// first. This relies on the admin socket being a single ordered stream,
+// where the Nth response always belongs to the Nth request written.
+// Callers push a channel under the same lock as the write that puts its
+// request on the wire, and the reader goroutine pops in the order
+// responses arrive.
+type pendingQueue struct {
+	mu    sync.Mutex
+	items []chan batchResult
+}
+
+func newPendingQueue() *pendingQueue {
+	return &pendingQueue{}
+}
+
+// push enqueues ch as the channel for the next request written to the
+// connection. Callers must hold the same lock guarding the write.
+func (q *pendingQueue) push(ch chan batchResult) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.items = append(q.items, ch)
+}
+
+// cancelLast removes ch from the back of the queue, for when the write it
+// was pushed for never made it onto the wire. Callers must hold the same
+// lock guarding the write.
+func (q *pendingQueue) cancelLast(ch chan batchResult) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if n := len(q.items); n > 0 && q.items[n-1] == ch {
+		q.items = q.items[:n-1]
+	}
+}
+
+// pop dequeues the channel for the oldest request still awaiting a
+// response. ok is false if no request is in flight.
+func (q *pendingQueue) pop() (ch chan batchResult, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.items) == 0 {
+		return nil, false
+	}
+	ch, q.items[0] = q.items[0], nil
+	q.items = q.items[1:]
+	return ch, true
+}
+
+// closeAll drains the queue, closing every still-pending channel so
+// in-flight requests stop waiting once the connection's read loop has
+// ended (e.g. the server closed the connection).
+func (q *pendingQueue) closeAll() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, ch := range q.items {
+		close(ch)
+	}
+	q.items = nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import "testing"
+
+func TestPendingQueueFIFO(t *testing.T) {
+	q := newPendingQueue()
+	a := make(chan batchResult, 1)
+	b := make(chan batchResult, 1)
+	q.push(a)
+	q.push(b)
+
+	got, ok := q.pop()
+	if !ok || got != a {
+		t.Fatalf("first pop = %v, %v; want a, true", got, ok)
+	}
+	got, ok = q.pop()
+	if !ok || got != b {
+		t.Fatalf("second pop = %v, %v; want b, true", got, ok)
+	}
+	if _, ok := q.pop(); ok {
+		t.Fatal("pop on empty queue returned ok = true")
+	}
+}
+
+func TestPendingQueueCancelLast(t *testing.T) {
+	q := newPendingQueue()
+	a := make(chan batchResult, 1)
+	b := make(chan batchResult, 1)
+	q.push(a)
+	q.push(b)
+	q.cancelLast(b)
+
+	got, ok := q.pop()
+	if !ok || got != a {
+		t.Fatalf("pop after cancelLast = %v, %v; want a, true", got, ok)
+	}
+	if _, ok := q.pop(); ok {
+		t.Fatal("expected cancelled entry to be gone")
+	}
+}
+
+func TestPendingQueueCancelLastOnlyRemovesTail(t *testing.T) {
+	q := newPendingQueue()
+	a := make(chan batchResult, 1)
+	b := make(chan batchResult, 1)
+	q.push(a)
+	q.push(b)
+	q.cancelLast(a) // a is not the tail, so this must be a no-op
+
+	got, ok := q.pop()
+	if !ok || got != a {
+		t.Fatalf("pop = %v, %v; want a, true", got, ok)
+	}
+}
+
+func TestPendingQueueCloseAll(t *testing.T) {
+	q := newPendingQueue()
+	a := make(chan batchResult, 1)
+	q.push(a)
+	q.closeAll()
+
+	if _, ok := <-a; ok {
+		t.Fatal("expected channel to be closed")
+	}
+	if _, ok := q.pop(); ok {
+		t.Fatal("expected queue to be empty after closeAll")
+	}
+}
@@ -0,0 +1,178 @@
+// Package renderer draws the getPeers/getDHT/getSessions tables shared by
+// the one-shot CLI and the -watch loop.
+package renderer
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Table renders the nested {key: {field: value}} shape returned by
+// getPeers, getSwitchPeers, getDHT and getSessions. It keeps the previous
+// snapshot around (keyed by box_pub_key, or coords when that's absent) so
+// repeated calls to Render can print rate columns instead of raw counters.
+type Table struct {
+	Verbose bool
+
+	prev     map[string]snapshot
+	prevTime time.Time
+	first    bool
+}
+
+type snapshot struct {
+	bytesSent  float64
+	bytesRecvd float64
+}
+
+// NewTable returns a Table with no prior snapshot, so the first Render call
+// always prints raw counters rather than rates.
+func NewTable(verbose bool) *Table {
+	return &Table{Verbose: verbose, first: true}
+}
+
+// Render prints res in table form. On every call after the first, it
+// replaces the raw bytes_sent and bytes_recvd columns with bytes_sent/s and
+// bytes_recvd/s columns computed against the previous snapshot, and marks rows whose key wasn't present last time with a
+// leading "+" and rows that disappeared with a trailing "(gone)" line.
+func (t *Table) Render(res map[string]interface{}) {
+	now := time.Now()
+	elapsed := now.Sub(t.prevTime).Seconds()
+	showRates := !t.first && elapsed > 0
+
+	maxWidths := make(map[string]int)
+	var keyOrder []string
+	keysOrdered := false
+
+	curr := make(map[string]snapshot)
+
+	for _, tlv := range res {
+		entries := tlv.(map[string]interface{})
+		for slk, slv := range entries {
+			fields := slv.(map[string]interface{})
+			if !keysOrdered {
+				for k := range fields {
+					if !t.Verbose {
+						if k == "box_pub_key" || k == "box_sig_key" || k == "nodeinfo" || k == "was_mtu_fixed" {
+							continue
+						}
+					}
+					if showRates && (k == "bytes_sent" || k == "bytes_recvd") {
+						continue
+					}
+					keyOrder = append(keyOrder, fmt.Sprint(k))
+				}
+				sort.Strings(keyOrder)
+				if showRates {
+					keyOrder = append(keyOrder, "bytes_sent/s", "bytes_recvd/s")
+				}
+				keysOrdered = true
+			}
+
+			entryKey := snapshotKey(slk, fields)
+			curr[entryKey] = snapshot{
+				bytesSent:  asFloat(fields["bytes_sent"]),
+				bytesRecvd: asFloat(fields["bytes_recvd"]),
+			}
+
+			if len(fmt.Sprint(slk)) > maxWidths["key"] {
+				maxWidths["key"] = len(fmt.Sprint(slk))
+			}
+			for k, v := range fields {
+				if len(fmt.Sprint(v)) > maxWidths[k] {
+					maxWidths[k] = len(fmt.Sprint(v))
+				}
+				if maxWidths[k] < len(k) {
+					maxWidths[k] = len(k)
+				}
+			}
+			for _, rate := range []string{"bytes_sent/s", "bytes_recvd/s"} {
+				if maxWidths[rate] < len(rate) {
+					maxWidths[rate] = len(rate)
+				}
+			}
+		}
+
+		if len(keyOrder) > 0 {
+			fmt.Printf("%-"+fmt.Sprint(maxWidths["key"])+"s  ", "")
+			for _, v := range keyOrder {
+				fmt.Printf("%-"+fmt.Sprint(maxWidths[v])+"s  ", v)
+			}
+			fmt.Println()
+		}
+
+		for slk, slv := range entries {
+			fields := slv.(map[string]interface{})
+			entryKey := snapshotKey(slk, fields)
+			marker := "  "
+			if showRates {
+				if _, existed := t.prev[entryKey]; !existed {
+					marker = "+ "
+				}
+			}
+			fmt.Print(marker)
+			fmt.Printf("%-"+fmt.Sprint(maxWidths["key"])+"s  ", slk)
+			for _, k := range keyOrder {
+				var formatted string
+				switch k {
+				case "bytes_sent/s":
+					formatted = rateSince(curr[entryKey].bytesSent, t.prev[entryKey].bytesSent, elapsed, showRates)
+				case "bytes_recvd/s":
+					formatted = rateSince(curr[entryKey].bytesRecvd, t.prev[entryKey].bytesRecvd, elapsed, showRates)
+				case "bytes_sent", "bytes_recvd":
+					formatted = fmt.Sprintf("%d", uint(asFloat(fields[k])))
+				case "uptime", "last_seen":
+					seconds := uint(asFloat(fields[k])) % 60
+					minutes := uint(asFloat(fields[k])/60) % 60
+					hours := uint(asFloat(fields[k]) / 60 / 60)
+					formatted = fmt.Sprintf("%02d:%02d:%02d", hours, minutes, seconds)
+				default:
+					formatted = fmt.Sprint(fields[k])
+				}
+				fmt.Printf("%-"+fmt.Sprint(maxWidths[k])+"s  ", formatted)
+			}
+			fmt.Println()
+		}
+
+		if showRates {
+			for key := range t.prev {
+				if _, stillThere := curr[key]; !stillThere {
+					fmt.Printf("-  %s (gone)\n", key)
+				}
+			}
+		}
+	}
+
+	t.prev = curr
+	t.prevTime = now
+	t.first = false
+}
+
+// snapshotKey identifies an entry across polls. box_pub_key is stable for
+// peers and sessions; DHT entries instead carry coords.
+func snapshotKey(fallback interface{}, fields map[string]interface{}) string {
+	if k, ok := fields["box_pub_key"].(string); ok && k != "" {
+		return k
+	}
+	if c, ok := fields["coords"].(string); ok && c != "" {
+		return c
+	}
+	return fmt.Sprint(fallback)
+}
+
+func asFloat(v interface{}) float64 {
+	f, _ := v.(float64)
+	return f
+}
+
+func rateSince(curr, prev, elapsedSeconds float64, show bool) string {
+	if !show {
+		return "-"
+	}
+	delta := curr - prev
+	if delta < 0 {
+		// counter reset, e.g. the peer reconnected
+		delta = 0
+	}
+	return fmt.Sprintf("%.1f", delta/elapsedSeconds)
+}
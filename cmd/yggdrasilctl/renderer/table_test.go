@@ -0,0 +1,46 @@
+package renderer
+
+import "testing"
+
+func TestRateSince(t *testing.T) {
+	cases := []struct {
+		name           string
+		curr, prev     float64
+		elapsedSeconds float64
+		show           bool
+		want           string
+	}{
+		{"hidden on first poll", 100, 0, 2, false, "-"},
+		{"steady rate", 200, 100, 2, true, "50.0"},
+		{"counter reset clamps to zero", 10, 100, 2, true, "0.0"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := rateSince(c.curr, c.prev, c.elapsedSeconds, c.show); got != c.want {
+				t.Errorf("rateSince(%v, %v, %v, %v) = %q, want %q", c.curr, c.prev, c.elapsedSeconds, c.show, got, c.want)
+			}
+		})
+	}
+}
+
+func TestSnapshotKey(t *testing.T) {
+	cases := []struct {
+		name     string
+		fallback interface{}
+		fields   map[string]interface{}
+		want     string
+	}{
+		{"prefers box_pub_key", "10.0.0.1", map[string]interface{}{"box_pub_key": "abcd", "coords": "[1 2]"}, "abcd"},
+		{"falls back to coords", "10.0.0.1", map[string]interface{}{"coords": "[1 2]"}, "[1 2]"},
+		{"falls back to the row key", "10.0.0.1", map[string]interface{}{}, "10.0.0.1"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := snapshotKey(c.fallback, c.fields); got != c.want {
+				t.Errorf("snapshotKey(%v, %v) = %q, want %q", c.fallback, c.fields, got, c.want)
+			}
+		})
+	}
+}
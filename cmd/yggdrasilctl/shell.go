@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chzyer/readline"
+	"gopkg.in/yaml.v2"
+)
+
+// shellVerbs mirrors the switch in handleAll: it's the set of request
+// verbs a human is actually expected to type at the prompt, used to seed
+// tab completion.
+var shellVerbs = []string{
+	"dot", "list", "getPeers", "getSwitchPeers", "getDHT", "getSessions", "dhtping",
+	"getTunTap", "setTunTap", "getSelf", "getSwitchQueues",
+	"addPeer", "removePeer", "addAllowedEncryptionPublicKey", "removeAllowedEncryptionPublicKey",
+	"addSourceSubnet", "removeSourceSubnet", "addRoute", "removeRoute",
+	"getAllowedEncryptionPublicKeys", "getMulticastInterfaces", "getSourceSubnets",
+	"getRoutes", "getTunnelRouting", "setTunnelRouting",
+}
+
+var shellMetaCommands = []string{".format", ".watch", ".help", ".exit"}
+
+// runShell implements `yggdrasilctl shell`: a readline-backed REPL over a
+// single persistent admin-socket connection, so exploratory sessions don't
+// each pay for a fresh dial and JSON handshake. Request rendering is
+// reused from handleAll; `.format` just picks which renderer handleAll (or
+// a raw json/yaml dump) ends up using.
+func runShell(cmdLineEnv *cmdLineEnv, logger *log.Logger) int {
+	conn := connect(cmdLineEnv.endpoint, cmdLineEnv.transportConfig(), logger)
+	defer conn.Close()
+
+	encoder := json.NewEncoder(conn)
+	decoder := json.NewDecoder(conn)
+
+	format := "table"
+	if cmdLineEnv.injson {
+		format = "json"
+	}
+
+	// getSelf doubles as an introspection call: its response keys become
+	// extra completions alongside the static verb list.
+	paramNames := discoverParamNames(encoder, decoder, logger)
+
+	completer := readline.NewPrefixCompleter(completionItems(paramNames)...)
+
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:          "yggdrasilctl> ",
+		HistoryFile:     historyFilePath(),
+		AutoComplete:    completer,
+		InterruptPrompt: "^C",
+		EOFPrompt:       "exit",
+	})
+	if err != nil {
+		logger.Println("Could not start shell:", err)
+		return 1
+	}
+	defer rl.Close()
+
+	for {
+		line, err := rl.Readline()
+		if err == readline.ErrInterrupt {
+			continue
+		}
+		if err == io.EOF {
+			return 0
+		}
+		if err != nil {
+			logger.Println("Readline error:", err)
+			return 1
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, ".") {
+			if line == ".exit" {
+				return 0
+			}
+			handleMetaCommand(line, &format, encoder, decoder, cmdLineEnv.verbose, logger)
+			continue
+		}
+
+		send := buildRequest(strings.Fields(line), logger)
+		recv, err := roundTrip(encoder, decoder, send)
+		if err != nil {
+			fmt.Println("Error:", err)
+			continue
+		}
+		renderShellResult(recv, format, cmdLineEnv.verbose)
+	}
+}
+
+// handleMetaCommand dispatches the `.format` and `.watch` meta-commands.
+func handleMetaCommand(line string, format *string, encoder *json.Encoder, decoder *json.Decoder, verbose bool, logger *log.Logger) {
+	fields := strings.Fields(line)
+	switch fields[0] {
+	case ".format":
+		if len(fields) != 2 {
+			fmt.Println("Usage: .format json|table|yaml")
+			return
+		}
+		switch fields[1] {
+		case "json", "table", "yaml":
+			*format = fields[1]
+		default:
+			fmt.Println("Unknown format:", fields[1])
+		}
+	case ".watch":
+		if len(fields) < 3 {
+			fmt.Println("Usage: .watch <cmd> <interval>")
+			return
+		}
+		interval, err := time.ParseDuration(fields[len(fields)-1])
+		if err != nil {
+			fmt.Println("Invalid interval:", err)
+			return
+		}
+		cmd := fields[1 : len(fields)-1]
+		watchUntilInterrupted(cmd, interval, *format, encoder, decoder, verbose, logger)
+	case ".help":
+		fmt.Println("Meta-commands:", strings.Join(shellMetaCommands, ", "))
+	default:
+		fmt.Println("Unknown meta-command:", fields[0])
+	}
+}
+
+// watchUntilInterrupted re-runs cmd every interval, the shell's analogue
+// of yggdrasilctl's own -watch/-follow flags, until ctrl-C.
+func watchUntilInterrupted(cmd []string, interval time.Duration, format string, encoder *json.Encoder, decoder *json.Decoder, verbose bool, logger *log.Logger) {
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+	defer signal.Stop(stop)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		send := buildRequest(cmd, logger)
+		recv, err := roundTrip(encoder, decoder, send)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		renderShellResult(recv, format, verbose)
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// renderShellResult prints one REPL response in whichever format the
+// `.format` meta-command last selected.
+func renderShellResult(recv map[string]interface{}, format string, verbose bool) {
+	res, ok := recv["response"].(map[string]interface{})
+	if !ok {
+		fmt.Println("Missing response body (malformed response?)")
+		return
+	}
+
+	switch format {
+	case "json":
+		if out, err := json.MarshalIndent(res, "", "  "); err == nil {
+			fmt.Println(string(out))
+		}
+	case "yaml":
+		if out, err := yaml.Marshal(res); err == nil {
+			fmt.Print(string(out))
+		}
+	default:
+		handleAll(recv, verbose)
+	}
+}
+
+// discoverParamNames issues a getSelf call at shell startup and collects
+// its response keys, so fields like "coords" or "box_pub_key" complete
+// alongside the request verbs even though they were never hardcoded.
+func discoverParamNames(encoder *json.Encoder, decoder *json.Decoder, logger *log.Logger) []string {
+	recv, err := roundTrip(encoder, decoder, admin_info{"request": "getSelf"})
+	if err != nil {
+		return nil
+	}
+	res, ok := recv["response"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	// The response is shaped {"self": {address: {field: value}}}, so the
+	// field names sit two levels down.
+	var names []string
+	for _, v := range res {
+		entries, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, e := range entries {
+			fields, ok := e.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for k := range fields {
+				names = append(names, k)
+			}
+		}
+	}
+	return names
+}
+
+func completionItems(paramNames []string) []readline.PrefixCompleterInterface {
+	items := make([]readline.PrefixCompleterInterface, 0, len(shellVerbs)+len(shellMetaCommands))
+	for _, verb := range shellVerbs {
+		items = append(items, readline.PcItem(verb))
+	}
+	for _, meta := range shellMetaCommands {
+		items = append(items, readline.PcItem(meta))
+	}
+	for _, name := range paramNames {
+		items = append(items, readline.PcItem(name+"="))
+	}
+	return items
+}
+
+func historyFilePath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".yggdrasilctl_history"
+	}
+	return filepath.Join(home, ".yggdrasilctl_history")
+}
+
@@ -0,0 +1,48 @@
+package transport
+
+import "net/url"
+
+// Config carries the authentication material that the tls://, wss:// and
+// ssh:// dialers need but that doesn't belong in an endpoint string.
+// Callers populate it from cmdLineEnv's -psk/-cert/-key/-cacert/-identity/
+// -hostkey flags; this tree has no yggdrasil.conf parser to source it from
+// an AdminAuth block instead.
+type Config struct {
+	// PSK is a pre-shared key used to authenticate tls:// connections
+	// instead of (or alongside) a certificate.
+	PSK string
+	// ClientCert and ClientKey point at a PEM keypair presented to the
+	// admin listener for client-certificate authentication.
+	ClientCert string
+	ClientKey  string
+	// RootCA pins the certificate authority that signed the admin
+	// listener's certificate, instead of trusting the system pool.
+	RootCA string
+	// IdentityFile is the SSH private key used by ssh:// when no agent
+	// is available.
+	IdentityFile string
+	// HostKeyFingerprint pins the SHA-256 fingerprint of the ssh://
+	// server's host key, mirroring how PSK pins the tls:// certificate.
+	HostKeyFingerprint string
+}
+
+// apply copies any fields that aren't already present as query parameters
+// on u, so an explicit `tls://host:port?psk=...` endpoint always wins over
+// the configured default.
+func (c *Config) apply(u *url.URL) *url.URL {
+	q := u.Query()
+	setDefault := func(key, value string) {
+		if value != "" && q.Get(key) == "" {
+			q.Set(key, value)
+		}
+	}
+	setDefault("psk", c.PSK)
+	setDefault("cert", c.ClientCert)
+	setDefault("key", c.ClientKey)
+	setDefault("cacert", c.RootCA)
+	setDefault("identity", c.IdentityFile)
+	setDefault("hostkey", c.HostKeyFingerprint)
+	u2 := *u
+	u2.RawQuery = q.Encode()
+	return &u2
+}
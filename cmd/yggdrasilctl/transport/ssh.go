@@ -0,0 +1,123 @@
+package transport
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func init() {
+	Register("ssh", DialerFunc(dialSSH))
+}
+
+// dialSSH reaches a remote admin socket over an SSH connection, e.g.
+// ssh://user@host/var/run/yggdrasil.sock. Authentication is delegated to a
+// running ssh-agent (identified via SSH_AUTH_SOCK), falling back to the
+// identity file named by the "identity" query parameter when no agent is
+// available. The returned connection is the remote unix socket tunneled
+// through the SSH session, not the SSH session itself.
+func dialSSH(u *url.URL) (net.Conn, error) {
+	if u.User == nil || u.User.Username() == "" {
+		return nil, fmt.Errorf("ssh endpoint must include a username, e.g. ssh://user@host%s", u.Path)
+	}
+
+	authMethods, err := sshAuthMethods(u)
+	if err != nil {
+		return nil, err
+	}
+
+	hostKeyCallback, err := sshHostKeyCallback(u)
+	if err != nil {
+		return nil, err
+	}
+
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "22")
+	}
+
+	client, err := ssh.Dial("tcp", host, &ssh.ClientConfig{
+		User:            u.User.Username(),
+		Auth:            authMethods,
+		HostKeyCallback: hostKeyCallback,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("dialing ssh host: %w", err)
+	}
+
+	remoteSocket := u.Path
+	if remoteSocket == "" {
+		remoteSocket = "/var/run/yggdrasil.sock"
+	}
+
+	conn, err := client.Dial("unix", remoteSocket)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("dialing remote admin socket %s: %w", remoteSocket, err)
+	}
+
+	return conn, nil
+}
+
+// sshHostKeyCallback pins the server's host key, the same way dialTLS pins
+// a certificate fingerprint with "psk": a "hostkey" query parameter gives
+// the expected SHA-256 fingerprint directly; otherwise the user's
+// known_hosts file is consulted, same as a regular ssh(1) client.
+func sshHostKeyCallback(u *url.URL) (ssh.HostKeyCallback, error) {
+	if fingerprint := u.Query().Get("hostkey"); fingerprint != "" {
+		want, err := hex.DecodeString(fingerprint)
+		if err != nil {
+			return nil, fmt.Errorf("hostkey is not valid hex: %w", err)
+		}
+		return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			got := sha256.Sum256(key.Marshal())
+			if hex.EncodeToString(got[:]) != hex.EncodeToString(want) {
+				return fmt.Errorf("host key fingerprint for %s does not match configured hostkey", hostname)
+			}
+			return nil
+		}, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("no hostkey query parameter set and could not locate known_hosts: %w", err)
+	}
+
+	callback, err := knownhosts.New(filepath.Join(home, ".ssh", "known_hosts"))
+	if err != nil {
+		return nil, fmt.Errorf("no hostkey query parameter set and known_hosts could not be read: %w", err)
+	}
+	return callback, nil
+}
+
+func sshAuthMethods(u *url.URL) ([]ssh.AuthMethod, error) {
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if agentConn, err := net.Dial("unix", sock); err == nil {
+			a := agent.NewClient(agentConn)
+			return []ssh.AuthMethod{ssh.PublicKeysCallback(a.Signers)}, nil
+		}
+	}
+
+	identity := u.Query().Get("identity")
+	if identity == "" {
+		return nil, fmt.Errorf("no ssh-agent available and no identity query parameter set")
+	}
+
+	key, err := os.ReadFile(identity)
+	if err != nil {
+		return nil, fmt.Errorf("reading identity file: %w", err)
+	}
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("parsing identity file: %w", err)
+	}
+	return []ssh.AuthMethod{ssh.PublicKeys(signer)}, nil
+}
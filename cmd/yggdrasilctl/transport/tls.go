@@ -0,0 +1,102 @@
+package transport
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+)
+
+func init() {
+	Register("tls", DialerFunc(dialTLS))
+}
+
+// dialTLS dials tls://host:port, authenticating either with a pre-shared
+// key (via tls.Config.GetClientCertificate is not enough for PSK, so we
+// fall back to pinning the server's certificate fingerprint against psk)
+// or with a regular client keypair. query parameters:
+//
+//	psk=<hex>         pin the server certificate's SHA-256 fingerprint
+//	cert=<path>        client certificate presented to the admin listener
+//	key=<path>         private key matching cert
+//	cacert=<path>      CA certificate to verify the server against
+func dialTLS(u *url.URL) (net.Conn, error) {
+	cfg, err := tlsConfigFromQuery(u)
+	if err != nil {
+		return nil, err
+	}
+	return tls.Dial("tcp", u.Host, cfg)
+}
+
+// tlsConfigFromQuery builds a *tls.Config from the same psk/cert/key/cacert
+// query parameters dialTLS uses, so dialWSS can authenticate a wss://
+// connection the same way. Returns nil if none of those parameters are set.
+func tlsConfigFromQuery(u *url.URL) (*tls.Config, error) {
+	q := u.Query()
+	if q.Get("psk") == "" && q.Get("cacert") == "" && q.Get("cert") == "" && q.Get("key") == "" {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{
+		ServerName: u.Hostname(),
+	}
+
+	if psk := q.Get("psk"); psk != "" {
+		// A PSK pins the exact certificate fingerprint, so there is
+		// nothing left for the normal CA chain to verify.
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyPeerCertificate = verifyPSKFingerprint(psk)
+	} else if cacert := q.Get("cacert"); cacert != "" {
+		pool, err := loadCertPool(cacert)
+		if err != nil {
+			return nil, fmt.Errorf("loading cacert: %w", err)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if cert, key := q.Get("cert"), q.Get("key"); cert != "" && key != "" {
+		pair, err := tls.LoadX509KeyPair(cert, key)
+		if err != nil {
+			return nil, fmt.Errorf("loading client keypair: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{pair}
+	}
+
+	return cfg, nil
+}
+
+// verifyPSKFingerprint returns a tls.Config.VerifyPeerCertificate callback
+// that accepts the connection only if the server's leaf certificate hashes
+// to the configured pre-shared key.
+func verifyPSKFingerprint(psk string) func([][]byte, [][]*x509.Certificate) error {
+	want, err := hex.DecodeString(psk)
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if err != nil {
+			return fmt.Errorf("psk is not valid hex: %w", err)
+		}
+		if len(rawCerts) == 0 {
+			return fmt.Errorf("server presented no certificate")
+		}
+		got := sha256.Sum256(rawCerts[0])
+		if hex.EncodeToString(got[:]) != hex.EncodeToString(want) {
+			return fmt.Errorf("server certificate fingerprint does not match configured psk")
+		}
+		return nil
+	}
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
@@ -0,0 +1,71 @@
+// Package transport collapses the various ways yggdrasilctl can reach an
+// admin socket - a local unix socket, a TCP/TLS socket, a websocket, or an
+// SSH-forwarded unix socket - behind a single Dialer interface. Every
+// scheme-specific dial function lives in its own file and registers itself
+// in init(), so adding a new scheme never touches the callers in cmd/yggdrasilctl/main.go.
+package transport
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// Dialer opens a connection to an admin socket for one URL scheme.
+type Dialer interface {
+	// Dial connects to the admin socket described by u and returns the
+	// resulting connection. The returned net.Conn need not be backed by a
+	// real network socket - ssh and websocket dialers wrap a stream that
+	// satisfies the interface instead.
+	Dial(u *url.URL) (net.Conn, error)
+}
+
+// DialerFunc adapts a plain function to the Dialer interface.
+type DialerFunc func(u *url.URL) (net.Conn, error)
+
+func (f DialerFunc) Dial(u *url.URL) (net.Conn, error) {
+	return f(u)
+}
+
+var dialers = make(map[string]Dialer)
+
+// Register associates a Dialer with a URL scheme. Scheme-specific files call
+// this from init() so that importing the transport package is enough to
+// make the scheme available.
+func Register(scheme string, d Dialer) {
+	dialers[strings.ToLower(scheme)] = d
+}
+
+// Dial parses endpoint and hands it off to the Dialer registered for its
+// scheme. Config carries the authentication material (PSK, certificates,
+// SSH identities) that some schemes need and which has no place in a bare
+// endpoint string.
+func Dial(endpoint string, cfg *Config) (net.Conn, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("malformed endpoint %q: %w", endpoint, err)
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	d, ok := dialers[scheme]
+	if !ok {
+		return nil, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+
+	if cfg != nil {
+		u = cfg.apply(u)
+	}
+
+	return d.Dial(u)
+}
+
+// Schemes returns the list of currently registered schemes, mostly useful
+// for -help output and error messages.
+func Schemes() []string {
+	schemes := make([]string, 0, len(dialers))
+	for scheme := range dialers {
+		schemes = append(schemes, scheme)
+	}
+	return schemes
+}
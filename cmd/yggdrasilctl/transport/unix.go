@@ -0,0 +1,29 @@
+package transport
+
+import (
+	"net"
+	"net/url"
+)
+
+func init() {
+	Register("unix", DialerFunc(dialUnix))
+	Register("unixgram", DialerFunc(dialUnixgram))
+	// tcp is kept around unchanged for backwards compatibility with
+	// existing configs and scripts.
+	Register("tcp", DialerFunc(dialTCP))
+}
+
+// dialUnix dials a stream-oriented unix socket, e.g. unix:///var/run/ygg.sock
+func dialUnix(u *url.URL) (net.Conn, error) {
+	return net.Dial("unix", u.Path)
+}
+
+// dialUnixgram dials a datagram unix socket. This is mostly useful for admin
+// sockets exposed inside sandboxes where a stream socket can't be bound.
+func dialUnixgram(u *url.URL) (net.Conn, error) {
+	return net.Dial("unixgram", u.Path)
+}
+
+func dialTCP(u *url.URL) (net.Conn, error) {
+	return net.Dial("tcp", u.Host)
+}
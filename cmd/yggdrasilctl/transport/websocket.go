@@ -0,0 +1,82 @@
+package transport
+
+import (
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func init() {
+	Register("wss", DialerFunc(dialWSS))
+	Register("ws", DialerFunc(dialWSS))
+}
+
+// dialWSS opens a websocket to the admin listener and wraps it so the rest
+// of yggdrasilctl can treat it like any other net.Conn. Admin messages are
+// JSON text, so they're tunneled one websocket text frame per message -
+// this is what lets browser-based dashboards talk to the same endpoint
+// without a native TCP/unix socket. For wss://, the same psk/cert/key/cacert
+// query parameters dialTLS understands authenticate the underlying TLS
+// connection; they have no meaning for plain ws://.
+func dialWSS(u *url.URL) (net.Conn, error) {
+	dialer := websocket.Dialer{HandshakeTimeout: 10 * time.Second}
+
+	if u.Scheme == "wss" {
+		tlsConfig, err := tlsConfigFromQuery(u)
+		if err != nil {
+			return nil, err
+		}
+		dialer.TLSClientConfig = tlsConfig
+	}
+
+	ws, _, err := dialer.Dial(u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return &wsConn{ws: ws}, nil
+}
+
+// wsConn adapts a *websocket.Conn to net.Conn by buffering whatever is left
+// of the current frame between Read calls.
+type wsConn struct {
+	ws   *websocket.Conn
+	left []byte
+}
+
+func (c *wsConn) Read(b []byte) (int, error) {
+	for len(c.left) == 0 {
+		_, data, err := c.ws.ReadMessage()
+		if err != nil {
+			return 0, err
+		}
+		c.left = data
+	}
+	n := copy(b, c.left)
+	c.left = c.left[n:]
+	return n, nil
+}
+
+func (c *wsConn) Write(b []byte) (int, error) {
+	if err := c.ws.WriteMessage(websocket.TextMessage, b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (c *wsConn) Close() error         { return c.ws.Close() }
+func (c *wsConn) LocalAddr() net.Addr  { return c.ws.LocalAddr() }
+func (c *wsConn) RemoteAddr() net.Addr { return c.ws.RemoteAddr() }
+
+func (c *wsConn) SetDeadline(t time.Time) error {
+	return c.ws.UnderlyingConn().SetDeadline(t)
+}
+
+func (c *wsConn) SetReadDeadline(t time.Time) error {
+	return c.ws.UnderlyingConn().SetReadDeadline(t)
+}
+
+func (c *wsConn) SetWriteDeadline(t time.Time) error {
+	return c.ws.UnderlyingConn().SetWriteDeadline(t)
+}